@@ -0,0 +1,70 @@
+// Package auth manages storage and retrieval of credentials for the
+// network services that are described in Terraform's service discovery
+// documents, such as module registries and remote operations endpoints.
+//
+// This is not a general-purpose auth package; its goal is to support the
+// relatively-simple credentials model used by Terraform's own services,
+// where a single opaque credential (for now, a bearer token) is associated
+// with a service hostname as produced by the svchost package.
+package auth
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+// HostCredentials represents a valid credential for a particular hostname
+// to be used when making requests to that host.
+//
+// For now this is just able to append the credentials to an outgoing
+// request, but the interface is intended to be general enough that we
+// could support other credential types in the future, such as client
+// certificates or HOBA authentication, as long as they can be prepared
+// from the information in an http.Request alone.
+type HostCredentials interface {
+	// PrepareRequest modifies the given request in-place to apply the
+	// credentials, such as by adding an Authorization header.
+	PrepareRequest(req *http.Request)
+}
+
+// CredentialsSource is an interface implemented by types that know how
+// to locate credentials for a given hostname, returning nil (with no
+// error) if no credentials are available for that hostname.
+type CredentialsSource interface {
+	// ForHost returns credentials for the given hostname, or nil if no
+	// credentials are available for it. It returns an error only if the
+	// process of looking up credentials fails in some way that is not
+	// simply "no credentials available".
+	ForHost(host svchost.Hostname) (HostCredentials, error)
+}
+
+// NoCredentials is a CredentialsSource that never has any credentials
+// available, for use in situations where credentials support isn't
+// relevant, such as in many tests.
+var NoCredentials CredentialsSource = noCredentials{}
+
+type noCredentials struct{}
+
+func (s noCredentials) ForHost(host svchost.Hostname) (HostCredentials, error) {
+	return nil, nil
+}
+
+// CredentialsFromMap is a helper that interprets a map of raw, untyped
+// values -- as might be decoded from JSON -- as a HostCredentials value,
+// for use when implementing a CredentialsSource that reads credentials
+// from some external, untyped source such as a credentials file or a
+// credentials helper program.
+//
+// Currently the only supported scheme is a bearer token given in the
+// "token" key, producing a HostCredentialsToken. If in future we support
+// other credential types, this function will also support producing those,
+// with the scheme distinguished by which keys are present in the map.
+func CredentialsFromMap(m map[string]interface{}) HostCredentials {
+	if raw, ok := m["token"]; ok {
+		if token, ok := raw.(string); ok {
+			return HostCredentialsToken(token)
+		}
+	}
+	return nil
+}
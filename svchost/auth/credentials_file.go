@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+// credentialsFileFormat is the JSON structure of a credentials block as
+// found in a CLI configuration file, such as ~/.terraformrc. The
+// "credentials" block itself maps a display-form hostname (as given by
+// the user) to an opaque object that CredentialsFromMap knows how to
+// interpret.
+type credentialsFileFormat struct {
+	Credentials map[string]map[string]interface{} `json:"credentials"`
+}
+
+// CredentialsFile is a CredentialsSource that reads credentials from a
+// JSON file on disk using the same "credentials" block syntax supported
+// by the CLI configuration file. Hostnames in the file are given in their
+// human-readable display form and are normalized via svchost.ForComparison
+// as they are loaded.
+type CredentialsFile struct {
+	filename string
+	creds    map[svchost.Hostname]HostCredentials
+}
+
+// LoadCredentialsFile reads and parses the credentials file at the given
+// path, returning a CredentialsSource that serves the credentials it
+// contains.
+//
+// If the file does not exist, this returns a CredentialsFile with no
+// credentials rather than an error, since an absent credentials file is
+// not itself a problem.
+func LoadCredentialsFile(filename string) (*CredentialsFile, error) {
+	ret := &CredentialsFile{
+		filename: filename,
+		creds:    map[svchost.Hostname]HostCredentials{},
+	}
+
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ret, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %s", filename, err)
+	}
+
+	var raw credentialsFileFormat
+	if err := json.Unmarshal(src, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", filename, err)
+	}
+
+	for givenHost, credBody := range raw.Credentials {
+		host, err := svchost.ForComparison(givenHost)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hostname %q in %s: %s", givenHost, filename, err)
+		}
+		if creds := CredentialsFromMap(credBody); creds != nil {
+			ret.creds[host] = creds
+		}
+	}
+
+	return ret, nil
+}
+
+// ForHost implements CredentialsSource.
+func (f *CredentialsFile) ForHost(host svchost.Hostname) (HostCredentials, error) {
+	return f.creds[host], nil
+}
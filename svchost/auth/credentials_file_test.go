@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+func TestLoadCredentialsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "terraform-auth-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "credentials.tfrc.json")
+	err = ioutil.WriteFile(filename, []byte(`
+		{
+			"credentials": {
+				"example.com": {
+					"token": "abc123"
+				}
+			}
+		}
+	`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := LoadCredentialsFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	creds, err := file.ForHost(svchost.Hostname("example.com"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, want := creds, HostCredentialsToken("abc123"); got != want {
+		t.Errorf("wrong credentials\ngot:  %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestLoadCredentialsFileMissing(t *testing.T) {
+	file, err := LoadCredentialsFile(filepath.Join(os.TempDir(), "does-not-exist.tfrc.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	creds, err := file.ForHost(svchost.Hostname("example.com"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if creds != nil {
+		t.Errorf("expected no credentials, got %#v", creds)
+	}
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+// helperProgramCredentialsSource is a CredentialsSource that delegates
+// credentials storage and retrieval to an external "credentials helper"
+// program, similar in spirit to the credential helper protocols used by
+// Docker and git. It implements CredentialsSource by shelling out to the
+// program for each request, as constructed by NewCredentialsHelperSource.
+type helperProgramCredentialsSource struct {
+	executable string
+	args       []string
+}
+
+// NewCredentialsHelperSource returns a CredentialsSource that retrieves and
+// stores credentials by executing the given program, passing it additional
+// arguments as given, and communicating over its stdin/stdout using a
+// simple JSON protocol.
+//
+// The program is invoked as:
+//
+//	executable args... get <hostname>
+//	executable args... store
+//	executable args... forget
+//
+// with "store" and "forget" passing a JSON request body on stdin. "get"
+// must either produce a JSON object containing the credentials (in the
+// same form accepted by CredentialsFromMap) on stdout, or produce no
+// output at all to indicate that the program has no credentials for the
+// requested host.
+func NewCredentialsHelperSource(executable string, args ...string) CredentialsSource {
+	return &helperProgramCredentialsSource{
+		executable: executable,
+		args:       args,
+	}
+}
+
+// ForHost implements CredentialsSource by invoking the helper program's
+// "get" subcommand.
+func (s *helperProgramCredentialsSource) ForHost(host svchost.Hostname) (HostCredentials, error) {
+	args := append(append([]string{}, s.args...), "get", string(host))
+	cmd := exec.Command(s.executable, args...)
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error running credentials helper %q: %s", s.executable, err)
+	}
+
+	if stdout.Len() == 0 {
+		return nil, nil
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &raw); err != nil {
+		return nil, fmt.Errorf("credentials helper %q produced invalid output: %s", s.executable, err)
+	}
+
+	return CredentialsFromMap(raw), nil
+}
+
+// StoreForHost stores credentials for the given host by invoking the
+// helper program's "store" subcommand, passing the host and credentials
+// as a JSON object on stdin.
+func (s *helperProgramCredentialsSource) StoreForHost(host svchost.Hostname, credentials map[string]interface{}) error {
+	body, err := json.Marshal(struct {
+		Host        string                 `json:"host"`
+		Credentials map[string]interface{} `json:"credentials"`
+	}{
+		Host:        string(host),
+		Credentials: credentials,
+	})
+	if err != nil {
+		return err
+	}
+
+	args := append(append([]string{}, s.args...), "store")
+	cmd := exec.Command(s.executable, args...)
+	cmd.Stdin = bytes.NewReader(body)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error running credentials helper %q: %s", s.executable, err)
+	}
+	return nil
+}
+
+// ForgetForHost discards any stored credentials for the given host by
+// invoking the helper program's "forget" subcommand.
+func (s *helperProgramCredentialsSource) ForgetForHost(host svchost.Hostname) error {
+	body, err := json.Marshal(struct {
+		Host string `json:"host"`
+	}{
+		Host: string(host),
+	})
+	if err != nil {
+		return err
+	}
+
+	args := append(append([]string{}, s.args...), "forget")
+	cmd := exec.Command(s.executable, args...)
+	cmd.Stdin = bytes.NewReader(body)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error running credentials helper %q: %s", s.executable, err)
+	}
+	return nil
+}
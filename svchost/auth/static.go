@@ -0,0 +1,23 @@
+package auth
+
+import "github.com/hashicorp/terraform/svchost"
+
+// StaticCredentialsSource is a CredentialsSource that retrieves credentials
+// from a map of hostname to pre-parsed HostCredentials provided at
+// construction time. It's intended primarily for use in tests, and for
+// assembling a small fixed set of built-in credentials.
+type StaticCredentialsSource struct {
+	creds map[svchost.Hostname]HostCredentials
+}
+
+// StaticCredentialsSourceForHosts returns a CredentialsSource with a fixed
+// set of credentials assigned to particular hosts, as given in the
+// "creds" map.
+func StaticCredentialsSourceForHosts(creds map[svchost.Hostname]HostCredentials) CredentialsSource {
+	return StaticCredentialsSource{creds: creds}
+}
+
+// ForHost implements CredentialsSource.
+func (s StaticCredentialsSource) ForHost(host svchost.Hostname) (HostCredentials, error) {
+	return s.creds[host], nil
+}
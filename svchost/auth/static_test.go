@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+func TestStaticCredentialsSource(t *testing.T) {
+	host := svchost.Hostname("example.com")
+	creds := HostCredentialsToken("abc123")
+
+	src := StaticCredentialsSourceForHosts(map[svchost.Hostname]HostCredentials{
+		host: creds,
+	})
+
+	got, err := src.ForHost(host)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != creds {
+		t.Errorf("wrong credentials\ngot:  %#v\nwant: %#v", got, creds)
+	}
+
+	got, err = src.ForHost(svchost.Hostname("other.example.com"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != nil {
+		t.Errorf("expected no credentials, got %#v", got)
+	}
+}
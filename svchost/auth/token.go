@@ -0,0 +1,16 @@
+package auth
+
+import "net/http"
+
+// HostCredentialsToken is a HostCredentials implementation that represents
+// a single bearer token, presented in the Authorization header.
+type HostCredentialsToken string
+
+// PrepareRequest alters the given HTTP request by setting its Authorization
+// header to present the bearer token represented by the receiver.
+func (tc HostCredentialsToken) PrepareRequest(req *http.Request) {
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Authorization", "Bearer "+string(tc))
+}
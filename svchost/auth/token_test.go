@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHostCredentialsTokenPrepareRequest(t *testing.T) {
+	creds := HostCredentialsToken("abc123")
+	req, err := http.NewRequest("GET", "http://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds.PrepareRequest(req)
+
+	if got, want := req.Header.Get("Authorization"), "Bearer abc123"; got != want {
+		t.Errorf("wrong Authorization header\ngot:  %s\nwant: %s", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+// Package disco handles Terraform's remote service discovery protocol.
+//
+// This protocol allows mapping from a service hostname, as produced by the
+// svchost package, to a set of services supported by that host and the
+// base URL for each supported service. It is the foundation on which
+// Terraform's module registry, provider registry and remote operations
+// features are all built.
+package disco
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/svchost"
+	"github.com/hashicorp/terraform/svchost/auth"
+)
+
+const discoPath = "/.well-known/terraform.json"
+const maxRedirects = 3 // arbitrary-but-small number to prevent runaway redirect loops
+const discoTimeout = 11 * time.Second
+
+// Disco is the main type in this package, representing a configuration
+// for service discovery plus a cache of results already performed.
+//
+// Most callers should use the package-level New function to construct a
+// Disco that uses sensible defaults, but some fields are exported for
+// situations (such as tests) where more control is required.
+type Disco struct {
+	hostCache map[svchost.Hostname]*Host
+	creds     auth.CredentialsSource
+
+	// Transport is a custom http.RoundTripper to use for discovery
+	// requests. If nil, http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	// HTTPClient is used to make the discovery request itself. It is
+	// initialized lazily from Transport the first time it's needed, so
+	// callers that want a custom transport should set that field rather
+	// than this one.
+	HTTPClient *http.Client
+}
+
+// New returns a new initialized Disco with no cached results and no
+// credentials source.
+func New() *Disco {
+	return &Disco{
+		hostCache: map[svchost.Hostname]*Host{},
+		creds:     auth.NoCredentials,
+	}
+}
+
+// SetCredentialsSource provides a source that will be used to add
+// credentials to outgoing discovery requests, where available, and that
+// will also be made available to callers via Host.PrepareRequest so they
+// can use the same credentials for subsequent requests to a discovered
+// service.
+func (d *Disco) SetCredentialsSource(src auth.CredentialsSource) {
+	d.creds = src
+}
+
+func (d *Disco) httpClient() *http.Client {
+	if d.HTTPClient == nil {
+		d.HTTPClient = &http.Client{
+			Transport: d.Transport,
+			Timeout:   discoTimeout,
+
+			// discover implements its own redirect handling, so that it can
+			// enforce maxRedirects and track the effective discovery URL
+			// across a cross-host redirect. Returning ErrUseLastResponse
+			// here disables the default client's automatic following,
+			// leaving each 3xx response for discover's loop to handle.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	return d.HTTPClient
+}
+
+// Discover runs the discovery protocol for the given hostname and returns
+// the discovered services, or an error if discovery failed.
+//
+// The returned *Host is cached in the receiver for the given hostname, so
+// subsequent calls with the same hostname will not incur an additional
+// discovery request unless ForgetHost is called first or the cached result
+// has expired (see Host.Expired).
+func (d *Disco) Discover(host svchost.Hostname) (*Host, error) {
+	if cached, ok := d.hostCache[host]; ok && !cached.Expired() {
+		return cached, nil
+	}
+
+	ret, err := d.discover(host)
+	if err != nil {
+		return nil, err
+	}
+
+	d.hostCache[host] = ret
+	return ret, nil
+}
+
+// ForgetHost discards any cached discovery result for the given hostname,
+// forcing the next call to Discover for that hostname to perform a fresh
+// discovery request.
+func (d *Disco) ForgetHost(host svchost.Hostname) {
+	delete(d.hostCache, host)
+}
+
+// discover implements the actual discovery protocol, as distinct from the
+// caching and bookkeeping done in Discover.
+func (d *Disco) discover(host svchost.Hostname) (*Host, error) {
+	discoURL := &url.URL{
+		Scheme: "https",
+		Host:   string(host),
+		Path:   discoPath,
+	}
+	client := d.httpClient()
+
+	creds, err := d.creds.ForHost(host)
+	if err != nil {
+		return nil, &ErrServiceDiscoveryNetworkRequest{hostname: host, err: err}
+	}
+
+	var body []byte
+	var maxAge time.Duration
+	for redirects := 0; ; redirects++ {
+		if redirects > maxRedirects {
+			return nil, &ErrServiceDiscoveryNetworkRequest{
+				hostname: host,
+				err:      fmt.Errorf("too many redirects"),
+			}
+		}
+
+		req, err := http.NewRequest("GET", discoURL.String(), nil)
+		if err != nil {
+			return nil, &ErrServiceDiscoveryNetworkRequest{hostname: host, err: err}
+		}
+		req.Header.Set("Accept", "application/json")
+		// Only attach credentials while we're still talking to the host
+		// they were issued for. The stdlib http.Client would normally strip
+		// Authorization/Cookie headers across a cross-origin redirect, but
+		// since we follow redirects ourselves we must apply that same
+		// protection explicitly.
+		if creds != nil && discoURL.Host == string(host) {
+			creds.PrepareRequest(req)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, &ErrServiceDiscoveryNetworkRequest{hostname: host, err: err}
+		}
+
+		switch {
+		case resp.StatusCode >= 300 && resp.StatusCode < 400:
+			// Follow the redirect, updating the effective hostname if the
+			// redirect takes us to a different host.
+			loc := resp.Header.Get("Location")
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			if loc == "" {
+				return nil, &ErrServiceDiscoveryNetworkRequest{
+					hostname: host,
+					err:      fmt.Errorf("redirect response with no Location header"),
+				}
+			}
+			next, err := discoURL.Parse(loc)
+			if err != nil {
+				return nil, &ErrServiceDiscoveryNetworkRequest{
+					hostname: host,
+					err:      fmt.Errorf("invalid redirect location: %s", err),
+				}
+			}
+			discoURL = next
+			continue
+
+		case resp.StatusCode == http.StatusOK:
+			maxAge = cacheMaxAge(resp.Header.Get("Cache-Control"))
+			body, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, &ErrServiceDiscoveryNetworkRequest{hostname: host, err: err}
+			}
+
+		default:
+			resp.Body.Close()
+			return nil, &ErrServiceDiscoveryNetworkRequest{
+				hostname: host,
+				err:      fmt.Errorf("unsuccessful request, status code %d", resp.StatusCode),
+			}
+		}
+
+		break
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, &ErrServiceDiscoveryParseError{hostname: host, err: err}
+	}
+
+	services := make(map[string]interface{}, len(raw))
+	for id, v := range raw {
+		urlStr, ok := v.(string)
+		if !ok {
+			// We only support string (URL) values right now. Future
+			// versions of this protocol may allow structured values, at
+			// which point we can relax this.
+			continue
+		}
+		serviceURL, err := discoURL.Parse(urlStr)
+		if err != nil {
+			return nil, &ErrServiceDiscoveryParseError{
+				hostname: host,
+				err:      fmt.Errorf("invalid URL for service %q: %s", id, err),
+			}
+		}
+		services[id] = serviceURL
+	}
+
+	return &Host{
+		hostname: host,
+		discoURL: discoURL,
+		services: services,
+		creds:    creds,
+		expires:  time.Now().Add(maxAge),
+	}, nil
+}
+
+// cacheMaxAge parses a Cache-Control header and returns the max-age
+// duration it specifies, or a small default if the header is absent or
+// unparseable.
+func cacheMaxAge(cacheControl string) time.Duration {
+	const defaultMaxAge = 1 * time.Hour
+
+	for _, part := range strings.Split(cacheControl, ",") {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "max-age=") {
+			continue
+		}
+		secs, err := strconv.Atoi(strings.TrimPrefix(part, "max-age="))
+		if err != nil || secs < 0 {
+			continue
+		}
+		return time.Duration(secs) * time.Second
+	}
+
+	return defaultMaxAge
+}
@@ -0,0 +1,208 @@
+package disco
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/terraform/svchost"
+	"github.com/hashicorp/terraform/svchost/auth"
+)
+
+func TestDiscover(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != discoPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"modules.v1": "/modules/v1/", "login.v1": "https://example.com/login/v1/"}`))
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := New()
+	d.Transport = server.Client().Transport
+	host := svchost.Hostname(serverURL.Host)
+
+	disco, err := d.discover(host)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	modulesURL, err := disco.ServiceURL("modules.v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, want := modulesURL.String(), server.URL+"/modules/v1/"; got != want {
+		t.Errorf("wrong modules.v1 URL\ngot:  %s\nwant: %s", got, want)
+	}
+
+	loginURL, err := disco.ServiceURL("login.v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, want := loginURL.String(), "https://example.com/login/v1/"; got != want {
+		t.Errorf("wrong login.v1 URL\ngot:  %s\nwant: %s", got, want)
+	}
+
+	if _, err := disco.ServiceURL("nonexistent.v1"); err == nil {
+		t.Fatal("expected error for unsupported service, got none")
+	}
+}
+
+// crossHostTransport returns an http.RoundTripper that trusts the TLS
+// certificates of both given test servers, so that discover can be made to
+// follow a redirect from one to the other as it would a real cross-host
+// redirect.
+func crossHostTransport(servers ...*httptest.Server) http.RoundTripper {
+	pool := x509.NewCertPool()
+	for _, server := range servers {
+		pool.AddCert(server.Certificate())
+	}
+	return &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}
+}
+
+func TestDiscoverRedirectsCrossHost(t *testing.T) {
+	finalServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != discoPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"modules.v1": "/modules/v1/"}`))
+	}))
+	defer finalServer.Close()
+
+	redirectCount := 0
+	redirectingServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirectCount++
+		http.Redirect(w, r, finalServer.URL+discoPath, http.StatusFound)
+	}))
+	defer redirectingServer.Close()
+
+	d := New()
+	d.Transport = crossHostTransport(redirectingServer, finalServer)
+
+	redirectingURL, err := url.Parse(redirectingServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := svchost.Hostname(redirectingURL.Host)
+
+	disco, err := d.discover(host)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if redirectCount != 1 {
+		t.Fatalf("redirecting server was hit %d times; want 1", redirectCount)
+	}
+
+	// A relative service URL must be resolved against the redirect target,
+	// not the original host, since the redirect moved discovery to a
+	// different host entirely.
+	modulesURL, err := disco.ServiceURL("modules.v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, want := modulesURL.String(), finalServer.URL+"/modules/v1/"; got != want {
+		t.Errorf("wrong modules.v1 URL\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestDiscoverRedirectsDropCredentialsCrossHost(t *testing.T) {
+	var gotAuthHeader string
+	finalServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != discoPath {
+			http.NotFound(w, r)
+			return
+		}
+		gotAuthHeader = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer finalServer.Close()
+
+	redirectingServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, finalServer.URL+discoPath, http.StatusFound)
+	}))
+	defer redirectingServer.Close()
+
+	redirectingURL, err := url.Parse(redirectingServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := svchost.Hostname(redirectingURL.Host)
+
+	d := New()
+	d.Transport = crossHostTransport(redirectingServer, finalServer)
+	d.SetCredentialsSource(auth.StaticCredentialsSourceForHosts(map[svchost.Hostname]auth.HostCredentials{
+		host: auth.HostCredentialsToken("super-secret"),
+	}))
+
+	if _, err := d.discover(host); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if gotAuthHeader != "" {
+		t.Errorf("credentials for %s were sent to the cross-host redirect target; got Authorization header %q", host, gotAuthHeader)
+	}
+}
+
+func TestDiscoverRedirectsTooMany(t *testing.T) {
+	var server *httptest.Server
+	requestCount := 0
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		http.Redirect(w, r, server.URL+discoPath, http.StatusFound)
+	}))
+	defer server.Close()
+
+	d := New()
+	d.Transport = server.Client().Transport
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := svchost.Hostname(serverURL.Host)
+
+	_, err = d.discover(host)
+	if err == nil {
+		t.Fatal("unexpected success; want error for too many redirects")
+	}
+	if requestCount > maxRedirects+1 {
+		t.Errorf("server was hit %d times; want at most %d", requestCount, maxRedirects+1)
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	tests := []struct {
+		CacheControl string
+		Want         int
+	}{
+		{"", 3600},
+		{"no-cache", 3600},
+		{"max-age=60", 60},
+		{"public, max-age=120", 120},
+		{"max-age=not-a-number", 3600},
+	}
+
+	for _, test := range tests {
+		t.Run(test.CacheControl, func(t *testing.T) {
+			got := int(cacheMaxAge(test.CacheControl).Seconds())
+			if got != test.Want {
+				t.Errorf("wrong result\ninput: %q\ngot:   %d\nwant:  %d", test.CacheControl, got, test.Want)
+			}
+		})
+	}
+}
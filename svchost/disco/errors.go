@@ -0,0 +1,42 @@
+package disco
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform/svchost"
+)
+
+// ErrServiceNotProvided is returned by Host.ServiceURL when the host's
+// discovery document does not include the requested service.
+type ErrServiceNotProvided struct {
+	hostname svchost.Hostname
+	service  string
+}
+
+func (e *ErrServiceNotProvided) Error() string {
+	return fmt.Sprintf("host %s does not provide service %q", e.hostname.String(), e.service)
+}
+
+// ErrServiceDiscoveryNetworkRequest is returned by Disco.Discover when the
+// discovery request itself could not be completed, such as due to a
+// network error or an unsuccessful HTTP response.
+type ErrServiceDiscoveryNetworkRequest struct {
+	hostname svchost.Hostname
+	err      error
+}
+
+func (e *ErrServiceDiscoveryNetworkRequest) Error() string {
+	return fmt.Sprintf("failed to request discovery document for %s: %s", e.hostname.String(), e.err)
+}
+
+// ErrServiceDiscoveryParseError is returned by Disco.Discover when the
+// discovery document was successfully retrieved but could not be
+// interpreted as a valid discovery document.
+type ErrServiceDiscoveryParseError struct {
+	hostname svchost.Hostname
+	err      error
+}
+
+func (e *ErrServiceDiscoveryParseError) Error() string {
+	return fmt.Sprintf("invalid discovery document for %s: %s", e.hostname.String(), e.err)
+}
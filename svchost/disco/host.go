@@ -0,0 +1,62 @@
+package disco
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/terraform/svchost"
+	"github.com/hashicorp/terraform/svchost/auth"
+)
+
+// Host represents the results of service discovery for a particular
+// hostname, making the discovered services' URLs available via ServiceURL.
+//
+// Host values are not safe for use from multiple concurrent goroutines,
+// matching the behavior of the Disco they were produced by.
+type Host struct {
+	hostname svchost.Hostname
+	discoURL *url.URL
+	services map[string]interface{}
+	creds    auth.HostCredentials
+	expires  time.Time
+}
+
+// PrepareRequest modifies the given request, adding the credentials that
+// were used (if any) to retrieve this discovery document, so that callers
+// can reuse them when making requests directly to the discovered service
+// URLs.
+func (h *Host) PrepareRequest(req *http.Request) {
+	if h.creds != nil {
+		h.creds.PrepareRequest(req)
+	}
+}
+
+// ServiceURL returns the URL associated with the given service identifier,
+// such as "modules.v1" or "login.v1", or an error if the service is not
+// supported by this host.
+func (h *Host) ServiceURL(id string) (*url.URL, error) {
+	raw, ok := h.services[id]
+	if !ok {
+		return nil, &ErrServiceNotProvided{hostname: h.hostname, service: id}
+	}
+
+	u, ok := raw.(*url.URL)
+	if !ok {
+		// Should not happen for any Host constructed by this package's
+		// own discovery protocol implementation.
+		return nil, fmt.Errorf("service %q has invalid URL", id)
+	}
+
+	// Return a copy so that callers cannot mutate our cached value.
+	ret := *u
+	return &ret, nil
+}
+
+// Expired returns true if the discovery result is past the TTL that was
+// advertised by the server (or the default TTL, if none was advertised)
+// and so should be re-discovered.
+func (h *Host) Expired() bool {
+	return time.Now().After(h.expires)
+}
@@ -1,6 +1,15 @@
 // Package svchost deals with the representations of the so-called "friendly
 // hostnames" that we use to represent systems that provide Terraform-native
 // remote services, such as module registry, remote operations, etc.
+//
+// Hostname values produced by this package's normalization functions
+// (ForComparison and ForRegistration) are guaranteed to be conformant with
+// the IDNA2008 validation rules, including the bidi rule (RFC 5893) and the
+// CONTEXTJ rules for the zero-width joiner and non-joiner. Downstream code
+// that consumes a Hostname -- for registry lookups, for TLS SNI, etc -- can
+// therefore rely on it never containing a label that is invalid under those
+// rules. The CONTEXTO rules (RFC 5892 Appendix A.3 onwards, such as those for
+// the Greek keraia or the Catalan middle dot) are not checked.
 package svchost
 
 import (
@@ -34,12 +43,39 @@ var displayProfile = idna.New(
 
 // comparisonProfile is a stricter idna profile that combines basic
 // normalization with validation rules that prevent use of ambiguous or
-// invalid hostnames.
+// invalid hostnames. It also enforces the RFC 5893 bidi rule and the
+// CONTEXTJ rules for joiners (RFC 5892 Appendix A.1 and A.2), since those
+// are part of what IDNA2008 requires of a conformant label and we don't want
+// to allow bidi-invalid or out-of-context joiner usage to round-trip through
+// ForComparison. The CONTEXTO rules for other context-dependent characters
+// are not checked.
+//
+// Unlike displayProfile, this does not use transitional processing: the
+// transitional mapping silently deletes deviation characters such as ZWJ
+// and ZWNJ before CheckJoiners ever sees them, which would defeat the
+// CONTEXTJ check above. Omitting it means a handful of deviation characters
+// (such as German sharp s) that would previously have been mapped to their
+// non-deviation equivalent are now instead encoded as themselves, matching
+// registrationProfile's treatment of the same characters.
 var comparisonProfile = idna.New(
 	idna.MapForLookup(),
-	idna.Transitional(true),
 	idna.VerifyDNSLength(true),
 	idna.ValidateLabels(true),
+	idna.BidiRule(),
+	idna.CheckJoiners(true),
+)
+
+// registrationProfile is a still-stricter idna profile that applies the
+// full set of validation rules IDNA2008 requires of a name at registration
+// time, rather than the more liberal rules we apply when merely looking up
+// an already-registered name in ForComparison. In particular it applies no
+// mapping at all, so inputs that ForComparison would normalize (such as
+// uppercase ASCII letters) are rejected rather than normalized. Like
+// comparisonProfile it uses non-transitional processing, so deviation
+// characters (such as German sharp s) are encoded as themselves.
+var registrationProfile = idna.New(
+	idna.ValidateForRegistration(),
+	idna.VerifyDNSLength(true),
 )
 
 // ForDisplay takes a user-specified hostname and returns a normalized form of
@@ -92,6 +128,15 @@ func ForComparison(given string) (Hostname, error) {
 		return Hostname(""), fmt.Errorf("empty string is not a valid hostname")
 	}
 
+	// Terraform resolves service hostnames on hot paths, such as module and
+	// provider installation, and the vast majority of those hostnames are
+	// already in ASCII comparison form (e.g. "registry.terraform.io"). For
+	// that common case we can skip the idna package entirely, avoiding both
+	// its allocations and the cost of re-scanning the string inside it.
+	if result, ok := asciiForComparison(given); ok {
+		return Hostname(result), nil
+	}
+
 	// First we'll apply our additional constraint that Punycode must not
 	// be given directly by the user. This is not an IDN specification
 	// requirement, but we prohibit it to force users to use human-readable
@@ -114,6 +159,56 @@ func ForComparison(given string) (Hostname, error) {
 	return Hostname(result), nil
 }
 
+// IsValidForRegistration returns true if the given user-specified hostname
+// is valid for registration as a new service hostname.
+//
+// This is a stricter check than IsValid: it rejects ambiguous inputs that
+// IsValid would silently normalize, because at registration time we would
+// rather force the operator to resolve the ambiguity -- for example, by
+// fixing the case of the input -- than risk creating a hostname that later
+// causes lookups for some other spelling of the same name to disagree
+// about whether they refer to the same service.
+func IsValidForRegistration(given string) bool {
+	_, err := ForRegistration(given)
+	return err == nil
+}
+
+// ForRegistration takes a user-specified hostname and returns a normalized
+// form of it suitable for registering a new service hostname, such as when
+// an operator is configuring a private module or provider registry.
+//
+// Unlike ForComparison, ForRegistration applies the full IDNA2008
+// registration validation rules (RFC 5891 section 4) without the
+// permissive case-mapping that ForComparison uses for compatibility with
+// older lookup-only software. This means
+// ForRegistration will reject some inputs, such as those using uppercase
+// ASCII letters, that ForComparison would accept by silently mapping them
+// to an unambiguous equivalent.
+//
+// The returned Hostname is not valid if the returned error is non-nil.
+func ForRegistration(given string) (Hostname, error) {
+	if given == "" {
+		return Hostname(""), fmt.Errorf("empty string is not a valid hostname")
+	}
+
+	labels := labelIter{orig: given}
+	for ; !labels.done(); labels.next() {
+		label := labels.label()
+		if strings.HasPrefix(label, acePrefix) {
+			return Hostname(""), fmt.Errorf(
+				"hostname label %q specified in punycode format; service hostnames must be given in unicode",
+				label,
+			)
+		}
+	}
+
+	result, err := registrationProfile.ToASCII(given)
+	if err != nil {
+		return Hostname(""), err
+	}
+	return Hostname(result), nil
+}
+
 // ForDisplay returns a version of the receiver that is appropriate for display
 // in the UI. This includes converting any punycode labels to their
 // corresponding Unicode characters.
@@ -139,3 +234,74 @@ func (h Hostname) String() string {
 func (h Hostname) GoString() string {
 	return fmt.Sprintf("svchost.Hostname(%q)", string(h))
 }
+
+// maxDNSNameLength and maxDNSLabelLength mirror the limits that
+// comparisonProfile enforces via idna.VerifyDNSLength, so that our fast
+// path rejects exactly the same inputs that the slow path would.
+const maxDNSNameLength = 253
+const maxDNSLabelLength = 63
+
+// asciiForComparison is a fast path for ForComparison that handles the
+// common case of a hostname that is already in ASCII comparison form: all
+// lowercase ASCII letters, digits, hyphens and dots, with no empty labels,
+// no punycode labels and no DNS length violations.
+//
+// If given is in that form, it is returned unchanged along with true.
+// Otherwise asciiForComparison returns ("", false) and the caller must fall
+// back to the slower idna-based path, which can also produce inputs that
+// this fast path would have rejected (such as uppercase ASCII, which idna
+// lower-cases rather than rejecting).
+func asciiForComparison(given string) (string, bool) {
+	if len(given) > maxDNSNameLength {
+		return "", false
+	}
+
+	labelStart := 0
+	for i := 0; i < len(given); i++ {
+		c := given[i]
+		switch {
+		case c == '.':
+			if !validASCIILabel(given[labelStart:i]) {
+				return "", false
+			}
+			labelStart = i + 1
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-':
+			if i-labelStart >= maxDNSLabelLength {
+				return "", false
+			}
+		default:
+			// Anything else -- uppercase ASCII, non-ASCII, invalid
+			// characters -- must go through the full idna processing.
+			return "", false
+		}
+	}
+
+	if !validASCIILabel(given[labelStart:]) {
+		return "", false
+	}
+
+	return given, true
+}
+
+// validASCIILabel returns true if label is acceptable as a single label
+// within asciiForComparison's fast path, applying the same RFC 5891
+// section 4.2.3.1 hyphen restrictions that comparisonProfile's
+// idna.ValidateLabels(true) enforces on the slow path: a label must not be
+// empty, must not begin or end with a hyphen, must not have hyphens in both
+// the third and fourth characters (which is reserved for ACE labels), and
+// must not itself be an unexpanded punycode label.
+func validASCIILabel(label string) bool {
+	if len(label) == 0 {
+		return false // empty label
+	}
+	if label[0] == '-' || label[len(label)-1] == '-' {
+		return false // leading or trailing hyphen
+	}
+	if len(label) >= 4 && label[2] == '-' && label[3] == '-' {
+		return false // reserved for ACE labels, e.g. "ab--cd"
+	}
+	if len(label) >= len(acePrefix) && label[:len(acePrefix)] == acePrefix {
+		return false // user-supplied punycode is rejected, not passed through
+	}
+	return true
+}
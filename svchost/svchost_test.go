@@ -93,6 +93,36 @@ func TestForComparison(t *testing.T) {
 			"",
 			true,
 		},
+		{
+			// Already-ASCII punycode given directly by the user must still
+			// be rejected, even though it'd otherwise take the all-ASCII
+			// fast path in ForComparison.
+			"xn--80akhbyknj4f.com",
+			"",
+			true,
+		},
+		{
+			// A leading hyphen is invalid per RFC 5891 section 4.2.3.1, and
+			// must be rejected by the all-ASCII fast path rather than passed
+			// through unchanged.
+			"-foo.com",
+			"",
+			true,
+		},
+		{
+			// Likewise for a trailing hyphen.
+			"foo-.com",
+			"",
+			true,
+		},
+		{
+			// Hyphens in both the third and fourth characters of a label are
+			// reserved for ACE labels (i.e. punycode) and so are rejected in
+			// any other label by the same RFC.
+			"ab--cd.com",
+			"",
+			true,
+		},
 	}
 
 	for _, test := range tests {
@@ -112,6 +142,136 @@ func TestForComparison(t *testing.T) {
 	}
 }
 
+func TestForRegistration(t *testing.T) {
+	tests := []struct {
+		Input string
+		Want  string
+		Err   bool
+	}{
+		{
+			"",
+			"",
+			true,
+		},
+		{
+			"example.com",
+			"example.com",
+			false,
+		},
+		{
+			// Unlike ForComparison, ForRegistration does not apply
+			// case-folding or any other mapping, so it requires the input
+			// to already be in its unambiguous registerable form.
+			"HashiCorp.com",
+			"",
+			true,
+		},
+		{
+			"испытание.com",
+			"xn--80akhbyknj4f.com",
+			false,
+		},
+		{
+			// Non-transitional processing, which ForComparison also uses,
+			// encodes the sharp s itself rather than mapping it to "ss",
+			// because IDNA2008 treats it as an ordinary letter rather than
+			// a deviation to normalize.
+			"straße.de",
+			"xn--strae-oqa.de",
+			false,
+		},
+		{
+			"blah..blah",
+			"",
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			got, err := ForRegistration(test.Input)
+			if (err != nil) != test.Err {
+				if test.Err {
+					t.Error("unexpected success; want error")
+				} else {
+					t.Errorf("unexpected error; want success\nerror: %s", err)
+				}
+			}
+			if string(got) != test.Want {
+				t.Errorf("wrong result\ninput: %s\ngot:   %s\nwant:  %s", test.Input, got, test.Want)
+			}
+		})
+	}
+}
+
+func TestForComparisonBidiAndContextRules(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Input string
+		Err   bool
+	}{
+		{
+			// A label consisting entirely of Arabic-script characters is a
+			// valid RTL label under the bidi rule.
+			"valid Arabic label",
+			"مثال.com",
+			false,
+		},
+		{
+			// The bidi rule requires an RTL label to end with either an RTL
+			// character or a digit; a trailing Latin letter violates that.
+			"Arabic label with trailing Latin letter",
+			"مثالx.com",
+			true,
+		},
+		{
+			// A Hebrew geresh (U+05F3) is a right-to-left character, so a
+			// Latin-script label containing it violates the bidi rule.
+			"Hebrew geresh in a Latin label",
+			"a׳b.com",
+			true,
+		},
+		{
+			// CONTEXTJ: a ZWJ is only permitted immediately after a virama,
+			// to join two characters that would otherwise form a ligature.
+			"ZWJ not preceded by a virama",
+			"a‍b.com", // ZWJ (U+200D)
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := ForComparison(test.Input)
+			if (err != nil) != test.Err {
+				if test.Err {
+					t.Error("unexpected success; want error")
+				} else {
+					t.Errorf("unexpected error; want success\nerror: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func TestForComparisonASCIIAllocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		_, err := ForComparison("example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("ForComparison allocated %v times per run; want 0", allocs)
+	}
+}
+
+func BenchmarkForComparisonASCII(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ForComparison("example.com")
+	}
+}
+
 func TestHostnameForDisplay(t *testing.T) {
 	tests := []struct {
 		Input string